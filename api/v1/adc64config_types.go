@@ -0,0 +1,95 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ADC64ConfigSpec is the desired state of one ADC64 device: the register
+// values it should hold and whether MStream should be running.
+type ADC64ConfigSpec struct {
+	// Device is the name of the target device as known to ifc.ControlServer.
+	Device string `json:"device"`
+
+	// Registers maps a hexadecimal register address to its desired
+	// hexadecimal value.
+	// +optional
+	Registers map[string]string `json:"registers,omitempty"`
+
+	// MStream is the desired MStream run state for Device.
+	// +optional
+	MStream bool `json:"mstream,omitempty"`
+}
+
+// RegisterDrift is a register whose live value did not match the spec
+// before the last reconcile corrected it.
+type RegisterDrift struct {
+	Addr string `json:"addr"`
+	Want string `json:"want"`
+	Got  string `json:"got"`
+}
+
+// ADC64ConfigStatus reports the outcome of the last reconcile.
+type ADC64ConfigStatus struct {
+	// LastAppliedGeneration is the .metadata.generation that was last
+	// successfully reconciled.
+	// +optional
+	LastAppliedGeneration int64 `json:"lastAppliedGeneration,omitempty"`
+
+	// Drift lists the registers that differed from spec at the last
+	// reconcile, before they were corrected.
+	// +optional
+	Drift []RegisterDrift `json:"drift,omitempty"`
+
+	// MStreamRunning reports the MStream run state last successfully
+	// applied to Device, so reconciliation only calls MStreamStart/Stop on
+	// an actual transition instead of re-issuing it every requeue.
+	// +optional
+	MStreamRunning bool `json:"mstreamRunning,omitempty"`
+
+	// Conditions represent the latest available observations of the
+	// device's state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Device",type=string,JSONPath=`.spec.device`
+// +kubebuilder:printcolumn:name="Applied",type=integer,JSONPath=`.status.lastAppliedGeneration`
+
+// ADC64Config is the Schema for the adc64configs API: the declarative
+// register/MStream configuration of one ADC64 device.
+type ADC64Config struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ADC64ConfigSpec   `json:"spec,omitempty"`
+	Status ADC64ConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ADC64ConfigList contains a list of ADC64Config.
+type ADC64ConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ADC64Config `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ADC64Config{}, &ADC64ConfigList{})
+}
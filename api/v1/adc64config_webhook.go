@@ -0,0 +1,64 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/runtime"
+	"regexp"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var hexPattern = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+
+// SetupWebhookWithManager registers the ADC64Config validating webhook.
+func (r *ADC64Config) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-control-go-adc-jinr-ru-v1-adc64config,mutating=false,failurePolicy=fail,sideEffects=None,groups=control.go-adc.jinr.ru,resources=adc64configs,verbs=create;update,versions=v1,name=vadc64config.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ADC64Config{}
+
+// ValidateCreate implements webhook.Validator.
+func (r *ADC64Config) ValidateCreate() (admission.Warnings, error) {
+	return nil, r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator.
+func (r *ADC64Config) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	return nil, r.validate()
+}
+
+// ValidateDelete implements webhook.Validator.
+func (r *ADC64Config) ValidateDelete() (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *ADC64Config) validate() error {
+	for addr, value := range r.Spec.Registers {
+		if !hexPattern.MatchString(addr) {
+			return fmt.Errorf("register address %q is not a 0x-prefixed hex value", addr)
+		}
+		if !hexPattern.MatchString(value) {
+			return fmt.Errorf("register value %q for addr %s is not a 0x-prefixed hex value", value, addr)
+		}
+	}
+	return nil
+}
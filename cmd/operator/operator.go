@@ -0,0 +1,104 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package operator
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	controlv1 "jinr.ru/greenlab/go-adc/api/v1"
+	"jinr.ru/greenlab/go-adc/controllers"
+	"jinr.ru/greenlab/go-adc/pkg/config"
+	"jinr.ru/greenlab/go-adc/pkg/log"
+	"jinr.ru/greenlab/go-adc/pkg/operator"
+	"jinr.ru/greenlab/go-adc/pkg/srv/control"
+)
+
+const (
+	MetricsAddrOptionName = "metrics-bind-address"
+	ProbeAddrOptionName   = "health-probe-bind-address"
+	LeaderElectOptionName = "leader-elect"
+	WebhookPortOptionName = "webhook-port"
+
+	leaderElectionID = "go-adc-operator.control.go-adc.jinr.ru"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(controlv1.AddToScheme(scheme))
+}
+
+// NewCommand runs the controller-runtime manager that reconciles
+// ADC64Config resources (api/v1, see config/crd) against devices exposed
+// by ifc.ControlServer.
+func NewCommand() *cobra.Command {
+	var metricsAddr, probeAddr string
+	var leaderElect bool
+	var webhookPort int
+
+	cmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Run the Kubernetes controller reconciling ADC64Config resources against live devices",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.ReadConfig()
+			if err != nil {
+				return err
+			}
+			ctrlServer, err := control.NewControlServer(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+
+			mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+				Scheme:                 scheme,
+				MetricsBindAddress:     metricsAddr,
+				HealthProbeBindAddress: probeAddr,
+				LeaderElection:         leaderElect,
+				LeaderElectionID:       leaderElectionID,
+				Port:                   webhookPort,
+			})
+			if err != nil {
+				return err
+			}
+
+			reconciler := &controllers.ADC64ConfigReconciler{
+				Client:     mgr.GetClient(),
+				Scheme:     mgr.GetScheme(),
+				Reconciler: operator.NewReconciler(ctrlServer),
+			}
+			if err := reconciler.SetupWithManager(mgr); err != nil {
+				return err
+			}
+			if err := (&controlv1.ADC64Config{}).SetupWebhookWithManager(mgr); err != nil {
+				return err
+			}
+
+			log.Debug("Starting operator manager: metrics: %s probes: %s leader-elect: %t",
+				metricsAddr, probeAddr, leaderElect)
+			return mgr.Start(ctrl.SetupSignalHandler())
+		},
+	}
+	cmd.Flags().StringVar(&metricsAddr, MetricsAddrOptionName, ":8080", "The address the metrics endpoint binds to")
+	cmd.Flags().StringVar(&probeAddr, ProbeAddrOptionName, ":8081", "The address the health probe endpoint binds to")
+	cmd.Flags().BoolVar(&leaderElect, LeaderElectOptionName, false,
+		"Enable leader election, so only one operator replica is active at a time")
+	cmd.Flags().IntVar(&webhookPort, WebhookPortOptionName, 9443, "Port the webhook server binds to")
+	return cmd
+}
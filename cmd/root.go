@@ -22,6 +22,7 @@ import (
 	"jinr.ru/greenlab/go-adc/cmd/discover"
 	"jinr.ru/greenlab/go-adc/cmd/mstream"
 	"jinr.ru/greenlab/go-adc/cmd/completion"
+	"jinr.ru/greenlab/go-adc/cmd/operator"
 	"jinr.ru/greenlab/go-adc/pkg/log"
 )
 
@@ -39,5 +40,6 @@ func NewRootCommand(out io.Writer) (*cobra.Command) {
 	cmd.AddCommand(discover.NewCommand())
 	cmd.AddCommand(mstream.NewCommand())
 	cmd.AddCommand(completion.NewCommand())
+	cmd.AddCommand(operator.NewCommand())
 	return cmd
 }
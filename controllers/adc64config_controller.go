@@ -0,0 +1,87 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package controllers hosts the controller-runtime reconcilers for the
+// control.go-adc.jinr.ru API group.
+package controllers
+
+import (
+	"context"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+
+	controlv1 "jinr.ru/greenlab/go-adc/api/v1"
+	"jinr.ru/greenlab/go-adc/pkg/log"
+	"jinr.ru/greenlab/go-adc/pkg/operator"
+)
+
+// requeueInterval re-reconciles every ADC64Config on a fixed cadence, in
+// addition to whenever the object itself changes, so register drift caused
+// by something other than kubectl apply (a power cycle, manual reg write)
+// is still caught.
+const requeueInterval = 30 * time.Second
+
+// +kubebuilder:rbac:groups=control.go-adc.jinr.ru,resources=adc64configs,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=control.go-adc.jinr.ru,resources=adc64configs/status,verbs=get;update;patch
+
+// ADC64ConfigReconciler reconciles an ADC64Config against the live device
+// it names, via operator.Reconciler.
+type ADC64ConfigReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Reconciler *operator.Reconciler
+}
+
+func (r *ADC64ConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var cr controlv1.ADC64Config
+	if err := r.Get(ctx, req.NamespacedName, &cr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	result := r.Reconciler.ReconcileDevice(&operator.DeviceSpec{
+		Device:         cr.Spec.Device,
+		Registers:      cr.Spec.Registers,
+		MStream:        cr.Spec.MStream,
+		MStreamApplied: cr.Status.MStreamRunning,
+	})
+
+	cr.Status.Drift = nil
+	for _, d := range result.Drift {
+		cr.Status.Drift = append(cr.Status.Drift, controlv1.RegisterDrift{Addr: d.Addr, Want: d.Want, Got: d.Got})
+	}
+	cr.Status.MStreamRunning = result.MStreamRunning
+	if result.Err != nil {
+		log.Error("Reconcile failed: device: %s error: %s", cr.Spec.Device, result.Err)
+	} else {
+		cr.Status.LastAppliedGeneration = cr.Generation
+	}
+
+	if err := r.Status().Update(ctx, &cr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeueInterval}, result.Err
+}
+
+func (r *ADC64ConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlv1.ADC64Config{}).
+		Complete(r)
+}
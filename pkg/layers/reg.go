@@ -37,6 +37,14 @@ type RegLayer struct {
 	RegOps []*RegOp
 }
 
+// NewRegLayer packs ops into a single RegLayer so a caller that wants to
+// batch several register reads/writes into one MLink transaction can build
+// the frame and hand it to whatever sends RegLayer frames on the wire,
+// instead of serializing and sending one RegOp at a time.
+func NewRegLayer(ops []*RegOp) *RegLayer {
+	return &RegLayer{RegOps: ops}
+}
+
 var RegLayerType = gopacket.RegisterLayerType(RegLayerNum,
 	gopacket.LayerTypeMetadata{Name: "RegLayerType", Decoder: gopacket.DecodeFunc(DecodeRegLayer)})
 
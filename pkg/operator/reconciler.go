@@ -0,0 +1,139 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package operator is the reconciliation core driven by
+// controllers.ADC64ConfigReconciler: it reads back the current register
+// values of a device with RegReadAll, diffs them against the desired
+// DeviceSpec, and issues the minimal set of RegWrite calls (plus MStream
+// start/stop) to converge.
+package operator
+
+import (
+	"fmt"
+	"jinr.ru/greenlab/go-adc/pkg/layers"
+	"jinr.ru/greenlab/go-adc/pkg/log"
+	"jinr.ru/greenlab/go-adc/pkg/srv/control/ifc"
+	"strconv"
+)
+
+// DeviceSpec is the desired state of a single device: the register values
+// it should hold and whether MStream should be running. Addr and value are
+// hexadecimal, same convention as RegHex in pkg/srv/control. It mirrors
+// api/v1.ADC64ConfigSpec without depending on the Kubernetes API machinery.
+type DeviceSpec struct {
+	Device    string
+	Registers map[string]string
+	MStream   bool
+
+	// MStreamApplied is the MStream run state last successfully applied to
+	// Device (mirrors api/v1.ADC64ConfigStatus.MStreamRunning). It lets
+	// ReconcileDevice call MStreamStart/Stop only on an actual transition
+	// instead of re-issuing it every requeue, since neither call is known
+	// to be a no-op when the device is already in that state.
+	MStreamApplied bool
+}
+
+// RegDrift describes one register whose live value did not match the spec
+// before reconciliation applied a write.
+type RegDrift struct {
+	Addr string
+	Want string
+	Got  string
+}
+
+// DeviceResult is the outcome of reconciling a single DeviceSpec.
+type DeviceResult struct {
+	Device string
+	Drift  []*RegDrift
+	Err    error
+
+	// MStreamRunning is the MStream run state applied to Device by this
+	// reconcile (or carried over from DeviceSpec.MStreamApplied if nothing
+	// changed). The caller persists it as the next MStreamApplied.
+	MStreamRunning bool
+}
+
+type Reconciler struct {
+	ctrl ifc.ControlServer
+}
+
+func NewReconciler(ctrl ifc.ControlServer) *Reconciler {
+	return &Reconciler{ctrl: ctrl}
+}
+
+// ReconcileDevice converges a single device to its desired state and
+// returns the drift it found and corrected, or the error it hit doing so.
+// It is called once per ADC64Config by ADC64ConfigReconciler.Reconcile.
+func (r *Reconciler) ReconcileDevice(ds *DeviceSpec) *DeviceResult {
+	result := &DeviceResult{Device: ds.Device, MStreamRunning: ds.MStreamApplied}
+
+	device, err := r.ctrl.GetDeviceByName(ds.Device)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	actual, err := device.RegReadAll()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	// canonical rewrites a hex string as 0x%04x so that spellings the
+	// webhook accepts (0xFF, 0x00ff) compare equal; unparsable input is
+	// left as is.
+	canonical := func(h string) string {
+		n, err := strconv.ParseUint(h, 0, 32)
+		if err != nil {
+			return h
+		}
+		return fmt.Sprintf("0x%04x", n)
+	}
+	actualValues := make(map[string]string, len(actual))
+	for _, reg := range actual {
+		addr, value := reg.Hex()
+		actualValues[canonical(addr)] = canonical(value)
+	}
+
+	for addr, want := range ds.Registers {
+		got := actualValues[canonical(addr)]
+		if got == canonical(want) {
+			continue
+		}
+		reg, err := layers.NewRegFromHex(addr, want)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if err := device.RegWrite(reg); err != nil {
+			result.Err = err
+			return result
+		}
+		log.Debug("Reconciled register drift: device: %s addr: %s want: %s got: %s", ds.Device, addr, want, got)
+		result.Drift = append(result.Drift, &RegDrift{Addr: addr, Want: want, Got: got})
+	}
+
+	if ds.MStream != ds.MStreamApplied {
+		if ds.MStream {
+			err = device.MStreamStart()
+		} else {
+			err = device.MStreamStop()
+		}
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.MStreamRunning = ds.MStream
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package auth implements the RBAC policy gating the control API: roles
+// (reader, operator, admin) are mapped to HTTP verbs and device-name globs
+// by a YAML policy file, and resolved from either a bearer token or the
+// common name of an mTLS client certificate.
+package auth
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path"
+)
+
+const (
+	RoleReader   = "reader"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// Rule grants role access to Actions on devices matching Devices globs
+// (e.g. "*", "adc64-*"). An action is not necessarily an HTTP method: routes
+// where the verb alone can't distinguish what a request does (MStream start
+// vs stop are both GET) key on a named action instead, see Middleware.
+type Rule struct {
+	Role    string   `yaml:"role"`
+	Actions []string `yaml:"actions"`
+	Devices []string `yaml:"devices"`
+}
+
+// Policy is the RBAC policy loaded from a YAML file: a set of Rules plus
+// the credentials that resolve to each role. Tokens maps a bearer token to
+// a role; CertRoles maps an mTLS client certificate common name to a role.
+type Policy struct {
+	Rules     []*Rule           `yaml:"rules"`
+	Tokens    map[string]string `yaml:"tokens"`
+	CertRoles map[string]string `yaml:"certRoles"`
+}
+
+// LoadPolicy reads and parses a Policy from a YAML file at path.
+func LoadPolicy(p string) (*Policy, error) {
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// RoleForToken resolves a bearer token to a role, ok is false if the token
+// is unknown.
+func (p *Policy) RoleForToken(token string) (role string, ok bool) {
+	role, ok = p.Tokens[token]
+	return
+}
+
+// RoleForCertCN resolves an mTLS client certificate common name to a role,
+// ok is false if the common name is unknown.
+func (p *Policy) RoleForCertCN(cn string) (role string, ok bool) {
+	role, ok = p.CertRoles[cn]
+	return
+}
+
+// Allow reports whether role may perform action against device, per the
+// first matching Rule.
+func (p *Policy) Allow(role, action, device string) bool {
+	for _, rule := range p.Rules {
+		if rule.Role != role {
+			continue
+		}
+		if !containsAction(rule.Actions, action) {
+			continue
+		}
+		if matchesAnyDevice(rule.Devices, device) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAction(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyDevice(globs []string, device string) bool {
+	for _, glob := range globs {
+		if ok, err := path.Match(glob, device); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package auth
+
+import "testing"
+
+func TestPolicyAllow(t *testing.T) {
+	policy := &Policy{
+		Rules: []*Rule{
+			{Role: RoleReader, Actions: []string{"GET"}, Devices: []string{"*"}},
+			{Role: RoleOperator, Actions: []string{"GET", "POST"}, Devices: []string{"adc64-*"}},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		role   string
+		action string
+		device string
+		want   bool
+	}{
+		{"reader can read any device", RoleReader, "GET", "adc64-1", true},
+		{"reader cannot write", RoleReader, "POST", "adc64-1", false},
+		{"operator can write matching glob", RoleOperator, "POST", "adc64-1", true},
+		{"operator cannot write non-matching device", RoleOperator, "POST", "other-1", false},
+		{"unknown role is denied", RoleAdmin, "GET", "adc64-1", false},
+		{"unknown action is denied", RoleOperator, "DELETE", "adc64-1", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := policy.Allow(c.role, c.action, c.device); got != c.want {
+				t.Errorf("Allow(%q, %q, %q) = %v, want %v", c.role, c.action, c.device, got, c.want)
+			}
+		})
+	}
+}
+
+func TestPolicyRoleForToken(t *testing.T) {
+	policy := &Policy{Tokens: map[string]string{"tok-1": RoleAdmin}}
+
+	if role, ok := policy.RoleForToken("tok-1"); !ok || role != RoleAdmin {
+		t.Errorf("RoleForToken(tok-1) = %q, %v, want %q, true", role, ok, RoleAdmin)
+	}
+	if _, ok := policy.RoleForToken("unknown"); ok {
+		t.Errorf("RoleForToken(unknown) = ok, want not ok")
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package auth
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+)
+
+// DeviceVar is the mux route variable the Middleware reads the target
+// device name from. A protected route that does not capture {device} (such
+// as MStream start/stop for all devices) is checked against the empty
+// device name, which a "*" glob matches and a glob like "adc64-*" does not.
+const DeviceVar = "device"
+
+// ActionFunc computes the Policy action a request is attempting, so
+// Middleware can check it against the caller's role. Routes where the HTTP
+// method already distinguishes the operation can use ActionFromMethod;
+// routes that overload one method across several operations (MStream
+// start/stop are both GET) need one that looks deeper into the request,
+// e.g. at a mux route variable.
+type ActionFunc func(r *http.Request) string
+
+// ActionFromMethod is the ActionFunc for routes where the HTTP method is
+// itself a faithful action, e.g. "POST" for a write-only route.
+func ActionFromMethod(r *http.Request) string {
+	return r.Method
+}
+
+// Middleware returns a mux middleware that resolves a caller's role from
+// either a bearer token or an mTLS client certificate CN and rejects the
+// request with 401 if no credential resolves to a role, or 403 if the role
+// is not allowed to perform action(r) against its {device}.
+func Middleware(policy *Policy, action ActionFunc) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := roleFromRequest(policy, r)
+			if !ok {
+				http.Error(w, "missing or unknown credentials", http.StatusUnauthorized)
+				return
+			}
+
+			device := mux.Vars(r)[DeviceVar]
+			if !policy.Allow(role, action(r), device) {
+				http.Error(w, "role is not permitted to perform this operation", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func roleFromRequest(policy *Policy, r *http.Request) (string, bool) {
+	if r.TLS != nil {
+		for _, cert := range r.TLS.PeerCertificates {
+			if role, ok := policy.RoleForCertCN(cert.Subject.CommonName); ok {
+				return role, true
+			}
+		}
+	}
+
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if role, ok := policy.RoleForToken(token); ok {
+			return role, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,299 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package capture spools decoded MStream frames to disk as Parquet so a run
+// can be replayed later instead of only being consumed live. Each run is one
+// Parquet file with schema {device, channel, timestamp_ns, adc_samples[]},
+// rotated by size or age. Recorder is the type frames are written into;
+// ApiServer feeds it from the MStream frame broker while a capture runs.
+package capture
+
+import (
+	"context"
+	"fmt"
+	"jinr.ru/greenlab/go-adc/pkg/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+)
+
+// Frame is one decoded MStream sample batch, schema {device, channel,
+// timestamp_ns, adc_samples[]}.
+type Frame struct {
+	Device      string
+	Channel     uint32
+	TimestampNs uint64
+	AdcSamples  []uint32
+}
+
+// Run is one recorded capture: a single rotation of a device's capture file.
+type Run struct {
+	ID        string
+	Device    string
+	Path      string
+	StartedAt time.Time
+}
+
+func runFileName(device, id string) string {
+	return fmt.Sprintf("%s-%s.parquet", device, id)
+}
+
+// captureSchema is the Arrow schema every capture Parquet file is written
+// with. AdcSamples is a list column so a run can hold frames of differing
+// sample counts.
+var captureSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "device", Type: arrow.BinaryTypes.String},
+	{Name: "channel", Type: arrow.PrimitiveTypes.Uint32},
+	{Name: "timestamp_ns", Type: arrow.PrimitiveTypes.Uint64},
+	{Name: "adc_samples", Type: arrow.ListOf(arrow.PrimitiveTypes.Uint32)},
+}, nil)
+
+// Recorder spools Frame values to rotated per-device Parquet capture files
+// under dir. It is safe for concurrent use by multiple devices.
+type Recorder struct {
+	dir         string
+	rotateBytes int64
+	rotateEvery time.Duration
+	alloc       memory.Allocator
+
+	mu      sync.Mutex
+	writers map[string]*runWriter
+}
+
+type runWriter struct {
+	run     *Run
+	f       *os.File
+	writer  *pqarrow.FileWriter
+	builder *array.RecordBuilder
+}
+
+// NewRecorder creates a Recorder writing capture files under dir, rotating
+// a run once it reaches rotateBytes or rotateEvery, whichever comes first.
+// A zero rotateBytes or rotateEvery disables that rotation trigger.
+func NewRecorder(dir string, rotateBytes int64, rotateEvery time.Duration) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		dir:         dir,
+		rotateBytes: rotateBytes,
+		rotateEvery: rotateEvery,
+		alloc:       memory.NewGoAllocator(),
+		writers:     make(map[string]*runWriter),
+	}, nil
+}
+
+func (r *Recorder) openRun(device, id string, now time.Time) (*runWriter, error) {
+	run := &Run{ID: id, Device: device, Path: filepath.Join(r.dir, runFileName(device, id)), StartedAt: now}
+	f, err := os.Create(run.Path)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := pqarrow.NewFileWriter(captureSchema, f, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &runWriter{
+		run:     run,
+		f:       f,
+		writer:  writer,
+		builder: array.NewRecordBuilder(r.alloc, captureSchema),
+	}, nil
+}
+
+func (w *runWriter) close() error {
+	w.builder.Release()
+	if err := w.writer.Close(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// Start begins a new run for device, closing any run already in progress
+// for that device.
+func (r *Recorder) Start(device string, now time.Time) (*Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if w, ok := r.writers[device]; ok {
+		w.close()
+		delete(r.writers, device)
+	}
+
+	id := fmt.Sprintf("%d", now.UnixNano())
+	w, err := r.openRun(device, id, now)
+	if err != nil {
+		return nil, err
+	}
+	r.writers[device] = w
+	log.Debug("Started capture run: device: %s id: %s path: %s", device, id, w.run.Path)
+	return w.run, nil
+}
+
+// Write appends frame to device's current run as one Parquet row, rotating
+// to a new run first if the size or age threshold has been crossed. Write
+// is a no-op if no run is in progress for device.
+func (r *Recorder) Write(frame *Frame, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.writers[frame.Device]
+	if !ok {
+		return nil
+	}
+
+	if r.shouldRotate(w, now) {
+		if err := w.close(); err != nil {
+			return err
+		}
+		id := fmt.Sprintf("%d", now.UnixNano())
+		var err error
+		w, err = r.openRun(frame.Device, id, now)
+		if err != nil {
+			return err
+		}
+		r.writers[frame.Device] = w
+		log.Debug("Rotated capture run: device: %s id: %s path: %s", frame.Device, id, w.run.Path)
+	}
+
+	w.builder.Field(0).(*array.StringBuilder).Append(frame.Device)
+	w.builder.Field(1).(*array.Uint32Builder).Append(frame.Channel)
+	w.builder.Field(2).(*array.Uint64Builder).Append(frame.TimestampNs)
+	samples := w.builder.Field(3).(*array.ListBuilder)
+	samples.Append(true)
+	sampleValues := samples.ValueBuilder().(*array.Uint32Builder)
+	for _, s := range frame.AdcSamples {
+		sampleValues.Append(s)
+	}
+
+	rec := w.builder.NewRecord()
+	defer rec.Release()
+	return w.writer.Write(rec)
+}
+
+func (r *Recorder) shouldRotate(w *runWriter, now time.Time) bool {
+	if r.rotateEvery > 0 && now.Sub(w.run.StartedAt) >= r.rotateEvery {
+		return true
+	}
+	if r.rotateBytes > 0 {
+		if info, err := w.f.Stat(); err == nil && info.Size() >= r.rotateBytes {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop ends the run in progress for device, if any, flushing and closing
+// its Parquet file.
+func (r *Recorder) Stop(device string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.writers[device]
+	if !ok {
+		return nil
+	}
+	delete(r.writers, device)
+	return w.close()
+}
+
+// List returns the recorded runs for device, most recent first.
+func (r *Recorder) List(device string) ([]*Run, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := device + "-"
+	var runs []*Run
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".parquet") {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".parquet")
+		if strings.Contains(id, "-") {
+			// a run of another device whose name starts with device + "-"
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, &Run{
+			ID:        id,
+			Device:    device,
+			Path:      filepath.Join(r.dir, name),
+			StartedAt: info.ModTime(),
+		})
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs, nil
+}
+
+// Replay streams the frames of run id back through fn, in the order they
+// were recorded, so analysis tooling can re-consume a run offline through
+// the same pipeline that would process it live.
+func (r *Recorder) Replay(device, id string, fn func(*Frame) error) error {
+	path := filepath.Join(r.dir, runFileName(device, id))
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	arrowReader, err := pqarrow.NewFileReader(f, pqarrow.ArrowReadProperties{}, r.alloc)
+	if err != nil {
+		return err
+	}
+
+	rr, err := arrowReader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer rr.Release()
+
+	for rr.Next() {
+		rec := rr.Record()
+		deviceCol := rec.Column(0).(*array.String)
+		channelCol := rec.Column(1).(*array.Uint32)
+		tsCol := rec.Column(2).(*array.Uint64)
+		samplesCol := rec.Column(3).(*array.List)
+		sampleValues := samplesCol.ListValues().(*array.Uint32)
+
+		for row := 0; row < int(rec.NumRows()); row++ {
+			start, end := samplesCol.ValueOffsets(row)
+			frame := &Frame{
+				Device:      deviceCol.Value(row),
+				Channel:     channelCol.Value(row),
+				TimestampNs: tsCol.Value(row),
+				AdcSamples:  append([]uint32(nil), sampleValues.Uint32Values()[start:end]...),
+			}
+			if err := fn(frame); err != nil {
+				return err
+			}
+		}
+	}
+	return rr.Err()
+}
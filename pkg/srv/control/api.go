@@ -16,20 +16,36 @@ package control
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"io/ioutil"
 	"jinr.ru/greenlab/go-adc/pkg/config"
 	"jinr.ru/greenlab/go-adc/pkg/layers"
 	"jinr.ru/greenlab/go-adc/pkg/log"
 	"jinr.ru/greenlab/go-adc/pkg/srv"
+	"jinr.ru/greenlab/go-adc/pkg/srv/auth"
+	"jinr.ru/greenlab/go-adc/pkg/srv/capture"
+	ctrlgrpc "jinr.ru/greenlab/go-adc/pkg/srv/control/grpc"
 	"jinr.ru/greenlab/go-adc/pkg/srv/control/ifc"
+	"jinr.ru/greenlab/go-adc/pkg/srv/metrics"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 )
 
 const (
 	ApiPort = 8000
+
+	// CaptureDir is where recorded MStream runs are spooled by default.
+	CaptureDir         = "/var/lib/go-adc/capture"
+	CaptureRotateBytes = 512 * 1024 * 1024
+	CaptureRotateEvery = 10 * time.Minute
 )
 
 // RegHex ...
@@ -38,11 +54,48 @@ type RegHex struct {
 	Value string // hexadecimal
 }
 
+// RegBatchOp is one entry of a POST /api/reg/batch/{device} request body.
+type RegBatchOp struct {
+	Op    string `json:"op"` // "read" or "write"
+	Addr  string `json:"addr"`
+	Value string `json:"value,omitempty"` // ignored for "read"
+}
+
+// RegBatchResult is one entry of the response to a batch request: the op
+// echoed back with its resulting value (for "read", and for "write" once
+// applied) or an error if it failed.
+type RegBatchResult struct {
+	RegBatchOp
+	Error string `json:"error,omitempty"`
+}
+
+// RegBatcher is implemented by devices that can pack several RegOp entries
+// into a single RegLayer frame (see layers.NewRegLayer) and send it as one
+// MLink transaction, rather than issuing one round-trip per register.
+// Devices that don't implement it are still served by handleRegBatch,
+// sequentially.
+type RegBatcher interface {
+	RegBatch(ops []*layers.RegOp, atomic bool) ([]*layers.RegOp, error)
+}
+
 type ApiServer struct {
 	context.Context
 	*config.Config
 	*mux.Router
-	ctrl ifc.ControlServer
+	ctrl    ifc.ControlServer
+	metrics *metrics.Metrics
+	reg     *prometheus.Registry
+	capture *capture.Recorder
+	// grpc is the same ControlService implementation the gRPC control
+	// plane serves; the single-device reg read, reg write and MStream
+	// start/stop handlers delegate to it so both transports run through one
+	// code path. The batch, all-device MStream and capture handlers call
+	// ifc.ControlServer directly.
+	grpc   *ctrlgrpc.Server
+	frames *ctrlgrpc.Broker
+
+	captureMu    sync.Mutex
+	captureFeeds map[string]func()
 }
 
 var _ ifc.ApiServer = &ApiServer{}
@@ -50,43 +103,98 @@ var _ ifc.ApiServer = &ApiServer{}
 func NewApiServer(ctx context.Context, cfg *config.Config, ctrl ifc.ControlServer) (ifc.ApiServer, error) {
 	log.Debug("Initializing API server with address: %s port: %d", cfg.IP, ApiPort)
 
+	reg := prometheus.NewRegistry()
+	rec, err := capture.NewRecorder(CaptureDir, CaptureRotateBytes, CaptureRotateEvery)
+	if err != nil {
+		return nil, err
+	}
+	m := metrics.NewMetrics(reg)
+	frames := ctrlgrpc.NewBroker(m)
 	s := &ApiServer{
 		Context: ctx,
 		Config: cfg,
 		ctrl: ctrl,
+		metrics: m,
+		reg:     reg,
+		capture: rec,
+		grpc:    ctrlgrpc.NewServer(ctrl, frames),
+		frames:  frames,
+		captureFeeds: make(map[string]func()),
 	}
 	return s, nil
 }
 
-func (s *ApiServer) regReadHex(addr uint16, device string) (*RegHex, error) {
-	d, err := s.ctrl.GetDeviceByName(device)
+// startCaptureFeed subscribes to device's MStream frames on the broker and
+// writes each one to the capture run opened for device, until stopCaptureFeed
+// is called. It replaces any feed already running for device.
+func (s *ApiServer) startCaptureFeed(device string) {
+	frames, cancel, err := s.frames.Subscribe(device)
 	if err != nil {
-		return nil, err
+		log.Error("Failed to subscribe capture feed: device: %s error: %s", device, err)
+		return
+	}
+
+	s.captureMu.Lock()
+	if stop, ok := s.captureFeeds[device]; ok {
+		stop()
+	}
+	s.captureFeeds[device] = cancel
+	s.captureMu.Unlock()
+
+	go func() {
+		for frame := range frames {
+			err := s.capture.Write(&capture.Frame{
+				Device:      frame.Device,
+				Channel:     frame.Channel,
+				TimestampNs: frame.TimestampNs,
+				AdcSamples:  frame.AdcSamples,
+			}, time.Now())
+			if err != nil {
+				log.Error("Failed to write capture frame: device: %s error: %s", device, err)
+			}
+		}
+	}()
+}
+
+// stopCaptureFeed cancels the capture feed running for device, if any, and
+// closes its capture run. It is a no-op if device has no feed running.
+func (s *ApiServer) stopCaptureFeed(device string) {
+	s.captureMu.Lock()
+	stop, ok := s.captureFeeds[device]
+	if ok {
+		delete(s.captureFeeds, device)
+	}
+	s.captureMu.Unlock()
+
+	if ok {
+		stop()
+	}
+	if err := s.capture.Stop(device); err != nil {
+		log.Error("Failed to stop capture run: device: %s error: %s", device, err)
 	}
-	reg, err := d.RegRead(addr)
+}
+
+func (s *ApiServer) regReadHex(addr uint16, device string) (*RegHex, error) {
+	resp, err := s.grpc.RegRead(s.Context, &ctrlgrpc.RegReadRequest{Device: device, Addr: uint32(addr)})
+	hexAddr := fmt.Sprintf("0x%04x", addr)
+	s.metrics.RegRead(device, hexAddr, err)
 	if err != nil {
 		return nil, err
 	}
-	hexAddr, hexValue := reg.Hex()
 	return &RegHex{
-		Addr:  hexAddr,
-		Value: hexValue,
+		Addr:  fmt.Sprintf("0x%04x", resp.Addr),
+		Value: fmt.Sprintf("0x%04x", resp.Value),
 	}, nil
 }
 
 func (s *ApiServer) regReadAllHex(device string) ([]*RegHex, error) {
-	d, err := s.ctrl.GetDeviceByName(device)
-	if err != nil {
-		return nil, err
-	}
-	regs, err := d.RegReadAll()
+	resp, err := s.grpc.RegReadAll(s.Context, &ctrlgrpc.DeviceRequest{Device: device})
 	if err != nil {
 		return nil, err
 	}
-	var regsHex []*RegHex
-	for _, reg := range regs {
-		hexAddr, hexValue := reg.Hex()
-		regsHex = append(regsHex, &RegHex{ Addr: hexAddr, Value: hexValue })
+	regsHex := make([]*RegHex, len(resp.Regs))
+	for i, reg := range resp.Regs {
+		regsHex[i] = &RegHex{Addr: fmt.Sprintf("0x%04x", reg.Addr), Value: fmt.Sprintf("0x%04x", reg.Value)}
 	}
 	return regsHex, nil
 }
@@ -94,22 +202,93 @@ func (s *ApiServer) regReadAllHex(device string) ([]*RegHex, error) {
 // Start
 func (s *ApiServer) Run() error {
 	log.Debug("Starting API server: address: %s port: %d", s.Config.IP, ApiPort)
-	s.configureRouter()
+
+	go func() {
+		grpcAddr := fmt.Sprintf("%s:%d", s.Config.IP, ctrlgrpc.GrpcPort)
+		if err := ctrlgrpc.ListenAndServe(grpcAddr, s.ctrl, s.frames); err != nil {
+			log.Error("gRPC control server stopped: %s", err)
+		}
+	}()
+
+	if err := s.configureRouter(); err != nil {
+		return err
+	}
 	httpServer := &http.Server{
 		Handler: s.Router,
 		Addr:    fmt.Sprintf("%s:%d", s.Config.IP, ApiPort),
 	}
-	return httpServer.ListenAndServe()
+
+	if s.Config.TLSCertFile == "" {
+		return httpServer.ListenAndServe()
+	}
+
+	httpServer.TLSConfig = &tls.Config{}
+	if s.Config.TLSClientCAFile != "" {
+		caCert, err := ioutil.ReadFile(s.Config.TLSClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("failed to parse client CA certificate: %s", s.Config.TLSClientCAFile)
+		}
+		httpServer.TLSConfig.ClientCAs = clientCAs
+		httpServer.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	log.Debug("Serving API over TLS: cert: %s key: %s", s.Config.TLSCertFile, s.Config.TLSKeyFile)
+	return httpServer.ListenAndServeTLS(s.Config.TLSCertFile, s.Config.TLSKeyFile)
+}
+
+// mstreamRouteAction keys RBAC on the {action} route variable (start/stop)
+// rather than the request's HTTP method: every MStream route is GET, so the
+// method alone can't tell a register read from starting or stopping MStream
+// on a device.
+func mstreamRouteAction(r *http.Request) string {
+	return "mstream-" + mux.Vars(r)["action"]
 }
 
-func (s *ApiServer) configureRouter() {
+// configureRouter builds s.Router. If an RBAC policy file is configured, it
+// must load successfully: a security feature that silently falls open
+// because its policy failed to load is worse than one that refuses to
+// start, so a load error here is returned rather than logged and ignored.
+func (s *ApiServer) configureRouter() error {
 	s.Router = mux.NewRouter()
 	subRouter := s.Router.PathPrefix("/api").Subrouter()
 	subRouter.HandleFunc("/reg/r/{device}/{addr:0x[0-9abcdef]{4}}", s.handleRegRead()).Methods("GET")
 	subRouter.HandleFunc("/reg/r/{device}", s.handleRegReadAll()).Methods("GET")
-	subRouter.HandleFunc("/reg/w/{device}", s.handleRegWrite()).Methods("POST")
-	subRouter.HandleFunc("/mstream/{action:start|stop}/{device}", s.handleMStreamAction()).Methods("GET")
-	subRouter.HandleFunc("/mstream/{action:start|stop}", s.handleMStreamActionAll()).Methods("GET")
+	s.Router.Handle("/metrics", s.metrics.Handler(s.reg)).Methods("GET")
+
+	writeRouter := subRouter.PathPrefix("/reg/w").Subrouter()
+	writeRouter.HandleFunc("/{device}", s.handleRegWrite()).Methods("POST")
+
+	// reg/batch can write registers just like reg/w, so it is gated the same
+	// way rather than living on the unauthenticated subRouter.
+	batchRouter := subRouter.PathPrefix("/reg/batch").Subrouter()
+	batchRouter.HandleFunc("/{device}", s.handleRegBatch()).Methods("POST")
+
+	mstreamRouter := subRouter.PathPrefix("/mstream").Subrouter()
+	mstreamRouter.HandleFunc("/{action:start|stop}/{device}", s.handleMStreamAction()).Methods("GET")
+	mstreamRouter.HandleFunc("/{action:start|stop}", s.handleMStreamActionAll()).Methods("GET")
+
+	captureRouter := subRouter.PathPrefix("/capture").Subrouter()
+	captureRouter.HandleFunc("/{device}", s.handleCaptureStart()).Methods("POST")
+	captureRouter.HandleFunc("/{device}/list", s.handleCaptureList()).Methods("GET")
+	captureRouter.HandleFunc("/{device}/replay/{id}", s.handleCaptureReplay()).Methods("GET")
+
+	if s.Config.PolicyFile == "" {
+		return nil
+	}
+
+	policy, err := auth.LoadPolicy(s.Config.PolicyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load RBAC policy %s: %w", s.Config.PolicyFile, err)
+	}
+	writeRouter.Use(auth.Middleware(policy, auth.ActionFromMethod))
+	batchRouter.Use(auth.Middleware(policy, auth.ActionFromMethod))
+	mstreamRouter.Use(auth.Middleware(policy, mstreamRouteAction))
+	captureRouter.Use(auth.Middleware(policy, auth.ActionFromMethod))
+	return nil
 }
 
 func (s *ApiServer) handleRegRead() http.HandlerFunc {
@@ -163,18 +342,21 @@ func (s *ApiServer) handleRegWrite() http.HandlerFunc {
 		log.Debug("Handling reg write request: device: %s addr: %s value: %s",
 			vars["device"], regHex.Addr, regHex.Value)
 
-		reg, err := layers.NewRegFromHex(regHex.Addr, regHex.Value)
+		addr, err := strconv.ParseUint(regHex.Addr, 0, 32)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		device, err := s.ctrl.GetDeviceByName(vars["device"])
+		value, err := strconv.ParseUint(regHex.Value, 0, 32)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		err = device.RegWrite(reg)
+
+		_, err = s.grpc.RegWrite(s.Context, &ctrlgrpc.RegWriteRequest{
+			Device: vars["device"], Addr: uint32(addr), Value: uint32(value),
+		})
+		s.metrics.RegWrite(vars["device"], fmt.Sprintf("0x%04x", addr), err)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadGateway)
 			return
@@ -182,28 +364,163 @@ func (s *ApiServer) handleRegWrite() http.HandlerFunc {
 	}
 }
 
-func (s *ApiServer) handleMStreamAction() http.HandlerFunc {
+// handleRegBatch packs a JSON array of {op, addr, value} into one RegLayer
+// frame and sends it as a single MLink transaction when the device supports
+// RegBatcher, falling back to sequential reg/reg write calls otherwise.
+// Query flags: all-or-nothing aborts on the first failed op; atomic asks the
+// device to apply the whole batch as one operation.
+func (s *ApiServer) handleRegBatch() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
-		log.Debug("Handling MStream action request: device: %s action: %s", vars["device"], vars["action"])
+
+		var batchOps []RegBatchOp
+		err := json.NewDecoder(r.Body).Decode(&batchOps)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		allOrNothing := r.URL.Query().Get("all-or-nothing") == "true"
+		atomic := r.URL.Query().Get("atomic") == "true"
+
+		log.Debug("Handling reg batch request: device: %s ops: %d all-or-nothing: %t atomic: %t",
+			vars["device"], len(batchOps), allOrNothing, atomic)
+
 		device, err := s.ctrl.GetDeviceByName(vars["device"])
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+
+		results, err := s.regBatch(device, vars["device"], batchOps, allOrNothing, atomic)
+		if err != nil {
+			var parseErr *regBatchParseError
+			if errors.As(err, &parseErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		json.NewEncoder(w).Encode(results)
+	}
+}
+
+// regBatchParseError marks a batch op rejected for malformed client input
+// (addr/value) so handleRegBatch can return 400 instead of treating it like
+// a device/backend failure.
+type regBatchParseError struct {
+	err error
+}
+
+func (e *regBatchParseError) Error() string { return e.err.Error() }
+func (e *regBatchParseError) Unwrap() error { return e.err }
+
+// parseBatchOps validates and converts the client-supplied batch ops into
+// layers.RegOp, returning a *regBatchParseError for anything outside the
+// documented op/addr/value formats so the caller can tell a malformed
+// request apart from a device/backend failure.
+func parseBatchOps(batchOps []RegBatchOp) ([]*layers.RegOp, error) {
+	ops := make([]*layers.RegOp, len(batchOps))
+	for i, batchOp := range batchOps {
+		if batchOp.Op != "read" && batchOp.Op != "write" {
+			return nil, &regBatchParseError{fmt.Errorf("batch op %d: op must be \"read\" or \"write\", got %q", i, batchOp.Op)}
+		}
+		addr, err := strconv.ParseUint(batchOp.Addr, 0, 16)
+		if err != nil {
+			return nil, &regBatchParseError{err}
+		}
+		var value uint64
+		if batchOp.Op != "read" {
+			value, err = strconv.ParseUint(batchOp.Value, 0, 16)
+			if err != nil {
+				return nil, &regBatchParseError{err}
+			}
+		}
+		ops[i] = &layers.RegOp{Read: batchOp.Op == "read", RegNum: uint16(addr), RegValue: uint16(value)}
+	}
+	return ops, nil
+}
+
+func (s *ApiServer) regBatch(device ifc.Device, deviceName string, batchOps []RegBatchOp, allOrNothing, atomic bool) ([]*RegBatchResult, error) {
+	ops, err := parseBatchOps(batchOps)
+	if err != nil {
+		return nil, err
+	}
+
+	if batcher, ok := device.(RegBatcher); ok {
+		doneOps, err := batcher.RegBatch(ops, atomic)
+		if err != nil {
+			return nil, err
+		}
+		if len(doneOps) != len(batchOps) {
+			return nil, fmt.Errorf("device returned %d results for a %d-op batch", len(doneOps), len(batchOps))
+		}
+		results := make([]*RegBatchResult, len(doneOps))
+		for i, op := range doneOps {
+			result := &RegBatchResult{RegBatchOp: batchOps[i]}
+			if op.Read {
+				result.Value = fmt.Sprintf("0x%04x", op.RegValue)
+			} else {
+				result.Value = batchOps[i].Value
+			}
+			results[i] = result
+		}
+		return results, nil
+	}
+
+	results := make([]*RegBatchResult, 0, len(batchOps))
+	for i, batchOp := range batchOps {
+		result := &RegBatchResult{RegBatchOp: batchOp}
+		hexAddr := fmt.Sprintf("0x%04x", ops[i].RegNum)
+		if ops[i].Read {
+			reg, err := device.RegRead(ops[i].RegNum)
+			s.metrics.RegRead(deviceName, hexAddr, err)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				_, result.Value = reg.Hex()
+			}
+		} else {
+			reg, err := layers.NewRegFromHex(batchOp.Addr, batchOp.Value)
+			if err == nil {
+				err = device.RegWrite(reg)
+			}
+			s.metrics.RegWrite(deviceName, hexAddr, err)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Value = batchOp.Value
+			}
+		}
+		results = append(results, result)
+		if result.Error != "" && allOrNothing {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (s *ApiServer) handleMStreamAction() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		log.Debug("Handling MStream action request: device: %s action: %s", vars["device"], vars["action"])
+		req := &ctrlgrpc.DeviceRequest{Device: vars["device"]}
 		switch vars["action"] {
 		case "start":
-			err = device.MStreamStart()
-			if err != nil {
+			if _, err := s.grpc.MStreamStart(s.Context, req); err != nil {
 				http.Error(w, err.Error(), http.StatusBadGateway)
 				return
 			}
+			s.metrics.MStreamState(vars["device"], true)
 		case "stop":
-			err := device.MStreamStop()
-			if err != nil {
+			if _, err := s.grpc.MStreamStop(s.Context, req); err != nil {
 				http.Error(w, err.Error(), http.StatusBadGateway)
 				return
 			}
+			s.metrics.MStreamState(vars["device"], false)
+			s.stopCaptureFeed(vars["device"])
 		default:
 			err := srv.ErrUnknownOperation{
 				What: "Wrong MStream action. Must be one of start/stop",
@@ -219,20 +536,21 @@ func (s *ApiServer) handleMStreamActionAll() http.HandlerFunc {
 		log.Debug("Handling MStream action request for all devices: action: %s", vars["action"])
 		switch vars["action"] {
 		case "start":
-			for _, d := range s.ctrl.GetAllDevices() {
-				err := d.MStreamStart()
-				if err != nil {
+			for name, d := range s.ctrl.GetAllDevices() {
+				if err := d.MStreamStart(); err != nil {
 					http.Error(w, err.Error(), http.StatusBadGateway)
 					return
 				}
+				s.metrics.MStreamState(name, true)
 			}
 		case "stop":
-			for _, d := range s.ctrl.GetAllDevices() {
-				err := d.MStreamStop()
-				if err != nil {
+			for name, d := range s.ctrl.GetAllDevices() {
+				if err := d.MStreamStop(); err != nil {
 					http.Error(w, err.Error(), http.StatusBadGateway)
 					return
 				}
+				s.metrics.MStreamState(name, false)
+				s.stopCaptureFeed(name)
 			}
 		default:
 			err := srv.ErrUnknownOperation{
@@ -242,3 +560,72 @@ func (s *ApiServer) handleMStreamActionAll() http.HandlerFunc {
 		}
 	}
 }
+
+// handleCaptureStart starts MStream on the device, opens a new capture run,
+// and subscribes a feed on the frame broker that writes every decoded
+// MStream frame for the device into that run until the device's MStream is
+// stopped.
+func (s *ApiServer) handleCaptureStart() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		log.Debug("Handling capture start request: device: %s", vars["device"])
+
+		device, err := s.ctrl.GetDeviceByName(vars["device"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		run, err := s.capture.Start(vars["device"], time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := device.MStreamStart(); err != nil {
+			if stopErr := s.capture.Stop(vars["device"]); stopErr != nil {
+				log.Error("Failed to stop capture run after MStreamStart failure: device: %s error: %s", vars["device"], stopErr)
+			}
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		s.metrics.MStreamState(vars["device"], true)
+		s.startCaptureFeed(vars["device"])
+
+		json.NewEncoder(w).Encode(run)
+	}
+}
+
+func (s *ApiServer) handleCaptureList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		log.Debug("Handling capture list request: device: %s", vars["device"])
+
+		runs, err := s.capture.List(vars["device"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(runs)
+	}
+}
+
+// handleCaptureReplay streams the frames of a recorded run back as
+// newline-delimited JSON, in recording order, so offline tooling can
+// re-consume a run the same way it would consume MStream live.
+func (s *ApiServer) handleCaptureReplay() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		log.Debug("Handling capture replay request: device: %s id: %s", vars["device"], vars["id"])
+
+		enc := json.NewEncoder(w)
+		err := s.capture.Replay(vars["device"], vars["id"], func(frame *capture.Frame) error {
+			return enc.Encode(frame)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseBatchOpsValid(t *testing.T) {
+	ops, err := parseBatchOps([]RegBatchOp{
+		{Op: "read", Addr: "0x0001"},
+		{Op: "write", Addr: "0x0002", Value: "0x00ff"},
+	})
+	if err != nil {
+		t.Fatalf("parseBatchOps returned error: %s", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("got %d ops, want 2", len(ops))
+	}
+	if !ops[0].Read || ops[0].RegNum != 1 {
+		t.Errorf("ops[0] = %+v, want a read of 0x0001", ops[0])
+	}
+	if ops[1].Read || ops[1].RegNum != 2 || ops[1].RegValue != 0xff {
+		t.Errorf("ops[1] = %+v, want a write of 0x0002=0x00ff", ops[1])
+	}
+}
+
+func TestParseBatchOpsRejectsUnknownOp(t *testing.T) {
+	_, err := parseBatchOps([]RegBatchOp{{Op: "delete", Addr: "0x0010", Value: "0x0020"}})
+	if err == nil {
+		t.Fatal("parseBatchOps did not reject op \"delete\"")
+	}
+	var parseErr *regBatchParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("parseBatchOps error is %T, want *regBatchParseError", err)
+	}
+}
+
+func TestParseBatchOpsRejectsMalformedAddr(t *testing.T) {
+	_, err := parseBatchOps([]RegBatchOp{{Op: "read", Addr: "not-hex"}})
+	if err == nil {
+		t.Fatal("parseBatchOps did not reject a malformed addr")
+	}
+	var parseErr *regBatchParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("parseBatchOps error is %T, want *regBatchParseError", err)
+	}
+}
+
+func TestParseBatchOpsRejectsMalformedValue(t *testing.T) {
+	_, err := parseBatchOps([]RegBatchOp{{Op: "write", Addr: "0x0010", Value: "not-hex"}})
+	if err == nil {
+		t.Fatal("parseBatchOps did not reject a malformed value")
+	}
+	var parseErr *regBatchParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("parseBatchOps error is %T, want *regBatchParseError", err)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package grpc
+
+import (
+	"jinr.ru/greenlab/go-adc/pkg/srv/metrics"
+	"sync"
+)
+
+// Broker is the concrete FrameSource Server is wired with: the MStream
+// reader calls Publish as frames are decoded, and every subscriber
+// registered for that device via Subscribe gets a copy from that point
+// on. A subscriber that falls behind has frames dropped for it rather
+// than blocking the reader or other subscribers.
+//
+// Publish is also the one point every decoded MStream frame passes
+// through regardless of how many subscribers it has, so Broker doubles as
+// where the frame-counter and last-seen metrics get fed from. It cannot
+// feed the drop-counter or reassembly-latency metrics: those describe the
+// UDP reassembly stage below a frame already being decoded, which this
+// tree's snapshot doesn't include.
+type Broker struct {
+	mu      sync.Mutex
+	subs    map[string]map[chan *MStreamFrame]struct{}
+	metrics metrics.Recorder
+}
+
+// NewBroker creates a Broker. rec may be nil, in which case Publish does
+// not record metrics.
+func NewBroker(rec metrics.Recorder) *Broker {
+	return &Broker{subs: make(map[string]map[chan *MStreamFrame]struct{}), metrics: rec}
+}
+
+var _ FrameSource = &Broker{}
+
+func (b *Broker) Subscribe(device string) (<-chan *MStreamFrame, func(), error) {
+	ch := make(chan *MStreamFrame, 64)
+
+	b.mu.Lock()
+	if b.subs[device] == nil {
+		b.subs[device] = make(map[chan *MStreamFrame]struct{})
+	}
+	b.subs[device][ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		delete(b.subs[device], ch)
+		b.mu.Unlock()
+		close(ch)
+	}
+	return ch, cancel, nil
+}
+
+// Publish fans frame out to every live subscriber of frame.Device and
+// records it against the frame-count, byte-count and last-seen metrics.
+func (b *Broker) Publish(frame *MStreamFrame) {
+	if b.metrics != nil {
+		b.metrics.MStreamFrame(frame.Device, len(frame.AdcSamples)*4)
+		b.metrics.Seen(frame.Device, "mstream")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[frame.Device] {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package grpc
+
+import (
+	"jinr.ru/greenlab/go-adc/pkg/log"
+	"jinr.ru/greenlab/go-adc/pkg/srv/control/ifc"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// GrpcPort is the default port the ControlService gRPC server listens on,
+// alongside the REST API's ApiPort.
+const GrpcPort = 9000
+
+// ListenAndServe starts the ControlService gRPC server on addr and blocks
+// serving requests until the listener errors out. The grpc.Server is local
+// to this call, so nothing outside it can Stop it. ctrl and frames are
+// passed straight to NewServer.
+func ListenAndServe(addr string, ctrl ifc.ControlServer, frames FrameSource) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s := grpc.NewServer(grpc.ForceServerCodec(jsonCodec{}))
+	RegisterControlServiceServer(s, NewServer(ctrl, frames))
+
+	log.Debug("Starting gRPC control server: addr: %s", addr)
+	return s.Serve(lis)
+}
@@ -0,0 +1,208 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package grpc implements the control.ControlService gRPC service defined in
+// api/proto/control.proto. Server wraps the same ifc.ControlServer the REST
+// handlers in pkg/srv/control use, so the two transports stay in sync by
+// construction: neither has its own copy of the device logic, both just call
+// through to the control server.
+//
+// The request/response types here mirror api/proto/control.proto by hand
+// until the generated stubs (control.pb.go, control_grpc.pb.go) are checked
+// in by running protoc; Server's method set matches the ControlServiceServer
+// interface protoc-gen-go-grpc would produce from that file.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"jinr.ru/greenlab/go-adc/pkg/layers"
+	"jinr.ru/greenlab/go-adc/pkg/log"
+	"jinr.ru/greenlab/go-adc/pkg/srv/control/ifc"
+	"strconv"
+)
+
+type DeviceRequest struct {
+	Device string
+}
+
+type RegReadRequest struct {
+	Device string
+	Addr   uint32
+}
+
+type RegReadResponse struct {
+	Addr  uint32
+	Value uint32
+}
+
+type RegReadAllResponse struct {
+	Regs []*RegReadResponse
+}
+
+type RegWriteRequest struct {
+	Device string
+	Addr   uint32
+	Value  uint32
+}
+
+type RegWriteResponse struct{}
+
+type Empty struct{}
+
+type MStreamFrame struct {
+	Device      string
+	Channel     uint32
+	TimestampNs uint64
+	AdcSamples  []uint32
+}
+
+// MStreamSubscribeStream is the subset of the generated server-streaming
+// interface Server.SubscribeMStream needs: one Send per decoded frame.
+type MStreamSubscribeStream interface {
+	Send(*MStreamFrame) error
+	Context() context.Context
+}
+
+// FrameSource is implemented by whatever decodes MStream frames for a
+// device (the MStream UDP reader). It is deliberately narrow so this
+// package doesn't need to import the reader to multiplex its output to
+// gRPC subscribers.
+type FrameSource interface {
+	Subscribe(device string) (frames <-chan *MStreamFrame, cancel func(), err error)
+}
+
+// Server implements the ControlService gRPC service on top of an
+// ifc.ControlServer, the same interface the REST ApiServer uses.
+type Server struct {
+	ctrl   ifc.ControlServer
+	frames FrameSource
+}
+
+// NewServer creates a Server. frames may be nil, in which case
+// SubscribeMStream reports that no frame source is wired up yet.
+func NewServer(ctrl ifc.ControlServer, frames FrameSource) *Server {
+	return &Server{ctrl: ctrl, frames: frames}
+}
+
+func (s *Server) RegRead(ctx context.Context, req *RegReadRequest) (*RegReadResponse, error) {
+	device, err := s.ctrl.GetDeviceByName(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := device.RegRead(uint16(req.Addr))
+	if err != nil {
+		return nil, err
+	}
+	_, hexValue := reg.Hex()
+	value, err := strconv.ParseUint(hexValue, 0, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &RegReadResponse{Addr: req.Addr, Value: uint32(value)}, nil
+}
+
+func (s *Server) RegReadAll(ctx context.Context, req *DeviceRequest) (*RegReadAllResponse, error) {
+	device, err := s.ctrl.GetDeviceByName(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	regs, err := device.RegReadAll()
+	if err != nil {
+		return nil, err
+	}
+	resp := &RegReadAllResponse{Regs: make([]*RegReadResponse, len(regs))}
+	for i, reg := range regs {
+		hexAddr, hexValue := reg.Hex()
+		addr, err := strconv.ParseUint(hexAddr, 0, 32)
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.ParseUint(hexValue, 0, 32)
+		if err != nil {
+			return nil, err
+		}
+		resp.Regs[i] = &RegReadResponse{Addr: uint32(addr), Value: uint32(value)}
+	}
+	return resp, nil
+}
+
+func (s *Server) RegWrite(ctx context.Context, req *RegWriteRequest) (*RegWriteResponse, error) {
+	device, err := s.ctrl.GetDeviceByName(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := layers.NewRegFromHex(fmt.Sprintf("0x%04x", req.Addr), fmt.Sprintf("0x%04x", req.Value))
+	if err != nil {
+		return nil, err
+	}
+	if err := device.RegWrite(reg); err != nil {
+		return nil, err
+	}
+	return &RegWriteResponse{}, nil
+}
+
+func (s *Server) MStreamStart(ctx context.Context, req *DeviceRequest) (*Empty, error) {
+	device, err := s.ctrl.GetDeviceByName(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	return &Empty{}, device.MStreamStart()
+}
+
+func (s *Server) MStreamStop(ctx context.Context, req *DeviceRequest) (*Empty, error) {
+	device, err := s.ctrl.GetDeviceByName(req.Device)
+	if err != nil {
+		return nil, err
+	}
+	return &Empty{}, device.MStreamStop()
+}
+
+// SubscribeMStream streams decoded MStream frames for req.Device to stream
+// until the client disconnects or the source is exhausted.
+func (s *Server) SubscribeMStream(req *DeviceRequest, stream MStreamSubscribeStream) error {
+	if s.frames == nil {
+		return ErrNoFrameSource{Device: req.Device}
+	}
+	frames, cancel, err := s.frames.Subscribe(req.Device)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case frame, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(frame); err != nil {
+				log.Error("Failed to send MStream frame: device: %s error: %s", req.Device, err)
+				return err
+			}
+		}
+	}
+}
+
+// ErrNoFrameSource is returned by SubscribeMStream when the server was
+// constructed without a FrameSource to multiplex.
+type ErrNoFrameSource struct {
+	Device string
+}
+
+func (e ErrNoFrameSource) Error() string {
+	return "no MStream frame source configured for device: " + e.Device
+}
@@ -0,0 +1,153 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// This file is the hand-written stand-in for what protoc-gen-go-grpc would
+// generate from api/proto/control.proto (control_grpc.pb.go): the service
+// interface, its grpc.ServiceDesc, and the per-method handlers that decode
+// a request, invoke the interceptor chain and call through to
+// ControlServiceServer. It exists because this tree has no protoc to run;
+// regenerating it for real is a drop-in replacement once that's available.
+package grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+// ControlServiceServer is the server API for ControlService.
+type ControlServiceServer interface {
+	RegRead(context.Context, *RegReadRequest) (*RegReadResponse, error)
+	RegReadAll(context.Context, *DeviceRequest) (*RegReadAllResponse, error)
+	RegWrite(context.Context, *RegWriteRequest) (*RegWriteResponse, error)
+	MStreamStart(context.Context, *DeviceRequest) (*Empty, error)
+	MStreamStop(context.Context, *DeviceRequest) (*Empty, error)
+	SubscribeMStream(*DeviceRequest, MStreamSubscribeStream) error
+}
+
+var _ ControlServiceServer = &Server{}
+
+// RegisterControlServiceServer registers srv as the implementation of
+// ControlService on s.
+func RegisterControlServiceServer(s grpc.ServiceRegistrar, srv ControlServiceServer) {
+	s.RegisterService(&controlServiceServiceDesc, srv)
+}
+
+func controlServiceRegReadHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RegRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/control.ControlService/RegRead"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RegRead(ctx, req.(*RegReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func controlServiceRegReadAllHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RegReadAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/control.ControlService/RegReadAll"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RegReadAll(ctx, req.(*DeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func controlServiceRegWriteHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegWriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).RegWrite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/control.ControlService/RegWrite"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).RegWrite(ctx, req.(*RegWriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func controlServiceMStreamStartHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).MStreamStart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/control.ControlService/MStreamStart"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).MStreamStart(ctx, req.(*DeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func controlServiceMStreamStopHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServiceServer).MStreamStop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/control.ControlService/MStreamStop"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServiceServer).MStreamStop(ctx, req.(*DeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// controlServiceSubscribeMStreamServer adapts a grpc.ServerStream to the
+// MStreamSubscribeStream interface SubscribeMStream is written against.
+type controlServiceSubscribeMStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlServiceSubscribeMStreamServer) Send(m *MStreamFrame) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func controlServiceSubscribeMStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(DeviceRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(ControlServiceServer).SubscribeMStream(in, &controlServiceSubscribeMStreamServer{stream})
+}
+
+var controlServiceServiceDesc = grpc.ServiceDesc{
+	ServiceName: "control.ControlService",
+	HandlerType: (*ControlServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "RegRead", Handler: controlServiceRegReadHandler},
+		{MethodName: "RegReadAll", Handler: controlServiceRegReadAllHandler},
+		{MethodName: "RegWrite", Handler: controlServiceRegWriteHandler},
+		{MethodName: "MStreamStart", Handler: controlServiceMStreamStartHandler},
+		{MethodName: "MStreamStop", Handler: controlServiceMStreamStopHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "SubscribeMStream", Handler: controlServiceSubscribeMStreamHandler, ServerStreams: true},
+	},
+	Metadata: "api/proto/control.proto",
+}
@@ -0,0 +1,167 @@
+/*
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package metrics collects Prometheus metrics for devices managed by
+// ifc.ControlServer and for the MStream UDP pipeline. The MStream frame
+// broker in pkg/srv/control/grpc feeds samples in through the narrow
+// Recorder interface. ApiServer in pkg/srv/control is not decoupled this
+// way: it holds the concrete *Metrics and the prometheus.Registry its
+// /metrics route serves.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// Recorder is implemented by Metrics. The MStream frame broker depends only
+// on this interface, not on prometheus/client_golang; the control server's
+// register and MStream handlers call the same methods on a *Metrics.
+type Recorder interface {
+	RegRead(device, addr string, err error)
+	RegWrite(device, addr string, err error)
+	MStreamState(device string, running bool)
+	MStreamFrame(device string, bytes int)
+	MStreamFrameDropped(device string)
+	MStreamReassemblyLatency(device string, d time.Duration)
+	Seen(device, addr string)
+}
+
+// Metrics is the default Recorder implementation. It registers its
+// collectors with the given prometheus.Registerer and exposes them over
+// an http.Handler suitable for mounting on the API server's /metrics route.
+type Metrics struct {
+	regReadTotal    *prometheus.CounterVec
+	regReadErrors   *prometheus.CounterVec
+	regWriteTotal   *prometheus.CounterVec
+	regWriteErrors  *prometheus.CounterVec
+	mstreamRunning  *prometheus.GaugeVec
+	mstreamFrames   *prometheus.CounterVec
+	mstreamBytes    *prometheus.CounterVec
+	mstreamDropped  *prometheus.CounterVec
+	mstreamLatency  *prometheus.HistogramVec
+	deviceLastSeen  *prometheus.GaugeVec
+}
+
+const namespace = "go_adc"
+
+// NewMetrics creates a Metrics recorder and registers its collectors with reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	factory := promauto.With(reg)
+	return &Metrics{
+		regReadTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reg_read_total",
+			Help:      "Total number of register read operations.",
+		}, []string{"device", "addr"}),
+		regReadErrors: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reg_read_errors_total",
+			Help:      "Total number of failed register read operations.",
+		}, []string{"device", "addr"}),
+		regWriteTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reg_write_total",
+			Help:      "Total number of register write operations.",
+		}, []string{"device", "addr"}),
+		regWriteErrors: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reg_write_errors_total",
+			Help:      "Total number of failed register write operations.",
+		}, []string{"device", "addr"}),
+		mstreamRunning: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "mstream_running",
+			Help:      "Whether MStream is running for a device (1) or not (0).",
+		}, []string{"device"}),
+		mstreamFrames: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mstream_frames_total",
+			Help:      "Total number of MStream frames received.",
+		}, []string{"device"}),
+		mstreamBytes: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mstream_bytes_total",
+			Help:      "Total number of MStream payload bytes received.",
+		}, []string{"device"}),
+		mstreamDropped: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mstream_frames_dropped_total",
+			Help:      "Total number of MStream frames dropped during UDP reassembly.",
+		}, []string{"device"}),
+		mstreamLatency: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "mstream_reassembly_latency_seconds",
+			Help:      "Latency of MStream UDP fragment reassembly.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"device"}),
+		deviceLastSeen: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "device_last_seen_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful register operation or MStream frame from a device.",
+		}, []string{"device", "addr"}),
+	}
+}
+
+// Handler returns an http.Handler serving metrics in the OpenMetrics/Prometheus
+// text exposition format, for mounting on the API server's router.
+func (m *Metrics) Handler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) RegRead(device, addr string, err error) {
+	m.regReadTotal.WithLabelValues(device, addr).Inc()
+	if err != nil {
+		m.regReadErrors.WithLabelValues(device, addr).Inc()
+		return
+	}
+	m.deviceLastSeen.WithLabelValues(device, addr).SetToCurrentTime()
+}
+
+func (m *Metrics) RegWrite(device, addr string, err error) {
+	m.regWriteTotal.WithLabelValues(device, addr).Inc()
+	if err != nil {
+		m.regWriteErrors.WithLabelValues(device, addr).Inc()
+		return
+	}
+	m.deviceLastSeen.WithLabelValues(device, addr).SetToCurrentTime()
+}
+
+func (m *Metrics) MStreamState(device string, running bool) {
+	if running {
+		m.mstreamRunning.WithLabelValues(device).Set(1)
+	} else {
+		m.mstreamRunning.WithLabelValues(device).Set(0)
+	}
+}
+
+func (m *Metrics) MStreamFrame(device string, bytes int) {
+	m.mstreamFrames.WithLabelValues(device).Inc()
+	m.mstreamBytes.WithLabelValues(device).Add(float64(bytes))
+}
+
+func (m *Metrics) MStreamFrameDropped(device string) {
+	m.mstreamDropped.WithLabelValues(device).Inc()
+}
+
+func (m *Metrics) MStreamReassemblyLatency(device string, d time.Duration) {
+	m.mstreamLatency.WithLabelValues(device).Observe(d.Seconds())
+}
+
+func (m *Metrics) Seen(device, addr string) {
+	m.deviceLastSeen.WithLabelValues(device, addr).SetToCurrentTime()
+}